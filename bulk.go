@@ -0,0 +1,110 @@
+package sendwithus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	stderrors "errors"
+
+	"github.com/superhuman/backend/lib/errors"
+)
+
+// BulkOptions configures a SendBulk call.
+type BulkOptions struct {
+	// Concurrency is the number of worker goroutines used to send messages
+	// in parallel. A value <= 0 defaults to 1.
+	Concurrency int
+
+	// IdempotencyKeys, if set, must be the same length as the messages slice
+	// passed to SendBulk. IdempotencyKeys[i] is sent as the Idempotency-Key
+	// header for messages[i], so a retried SendBulk call won't double-send.
+	IdempotencyKeys []string
+}
+
+// BulkResult describes the outcome of a SendBulk call.
+type BulkResult struct {
+	// Results holds one entry per message, in the same order as the
+	// messages slice passed to SendBulk.
+	Results []BulkItemResult
+}
+
+// BulkItemResult describes the outcome of sending a single message as part
+// of a SendBulk call.
+type BulkItemResult struct {
+	Index   int
+	Success bool
+	Err     *SWUError
+}
+
+// SendBulk sends many emails with bounded concurrency. Results are reported
+// per-index rather than failing the whole batch on the first error.
+func (c *SWUClient) SendBulk(messages []*SWUEmail, opts *BulkOptions) (*BulkResult, error) {
+	return c.SendBulkCtx(context.Background(), messages, opts)
+}
+
+// SendBulkCtx sends many emails with bounded concurrency. Results are
+// reported per-index rather than failing the whole batch on the first
+// error.
+func (c *SWUClient) SendBulkCtx(ctx context.Context, messages []*SWUEmail, opts *BulkOptions) (*BulkResult, error) {
+	if opts == nil {
+		opts = &BulkOptions{}
+	}
+	if opts.IdempotencyKeys != nil && len(opts.IdempotencyKeys) != len(messages) {
+		return nil, errors.Wrap(fmt.Errorf("swu.go: IdempotencyKeys length (%d) must match messages length (%d)", len(opts.IdempotencyKeys), len(messages)))
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	result := &BulkResult{Results: make([]BulkItemResult, len(messages))}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i, email := range messages {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, email *SWUEmail) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			var idempotencyKey string
+			if opts.IdempotencyKeys != nil {
+				idempotencyKey = opts.IdempotencyKeys[i]
+			}
+			err := c.sendWithIdempotencyKey(ctx, email, idempotencyKey)
+			if err == nil {
+				result.Results[i] = BulkItemResult{Index: i, Success: true}
+				return
+			}
+			var swuErr *SWUError
+			if !stderrors.As(err, &swuErr) {
+				swuErr = newSWUError(nil, err.Error())
+			}
+			result.Results[i] = BulkItemResult{Index: i, Success: false, Err: swuErr}
+		}(i, email)
+	}
+	wg.Wait()
+
+	return result, nil
+}
+
+func (c *SWUClient) sendWithIdempotencyKey(ctx context.Context, email *SWUEmail, idempotencyKey string) error {
+	payload, err := json.Marshal(email)
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	header := make(http.Header)
+	if idempotencyKey != "" {
+		header.Set("Idempotency-Key", idempotencyKey)
+	}
+	if err := c.makeRequestWithHeader(ctx, "POST", "/send", bytes.NewReader(payload), nil, header); err != nil {
+		return errors.Wrap(err)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package sendwithus
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestClient(url string) *SWUClient {
+	c := New("test-key")
+	c.URL = url
+	return c
+}
+
+func TestSendBulkBoundsConcurrency(t *testing.T) {
+	const (
+		total       = 8
+		concurrency = 2
+	)
+	var (
+		inFlight int32
+		maxSeen  int32
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cur := atomic.AddInt32(&inFlight, 1)
+		for {
+			seen := atomic.LoadInt32(&maxSeen)
+			if cur <= seen || atomic.CompareAndSwapInt32(&maxSeen, seen, cur) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	c := newTestClient(server.URL)
+	messages := make([]*SWUEmail, total)
+	for i := range messages {
+		messages[i] = &SWUEmail{Recipient: &SWURecipient{Address: "user@example.com"}}
+	}
+
+	result, err := c.SendBulk(messages, &BulkOptions{Concurrency: concurrency})
+	if err != nil {
+		t.Fatalf("SendBulk returned error: %v", err)
+	}
+	for i, item := range result.Results {
+		if !item.Success {
+			t.Fatalf("message %d failed unexpectedly: %+v", i, item.Err)
+		}
+	}
+
+	if got := atomic.LoadInt32(&maxSeen); got > concurrency {
+		t.Fatalf("observed %d concurrent requests, want at most %d", got, concurrency)
+	}
+}
+
+func TestSendBulkResultsMatchIndex(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var email SWUEmail
+		_ = json.Unmarshal(body, &email)
+		if strings.Contains(email.Recipient.Address, "fail") {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"message":"invalid recipient"}`))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	c := newTestClient(server.URL)
+	const total = 20
+	messages := make([]*SWUEmail, total)
+	for i := range messages {
+		addr := "ok-" + strconv.Itoa(i) + "@example.com"
+		if i%3 == 0 {
+			addr = "fail-" + strconv.Itoa(i) + "@example.com"
+		}
+		messages[i] = &SWUEmail{Recipient: &SWURecipient{Address: addr}}
+	}
+
+	result, err := c.SendBulk(messages, &BulkOptions{Concurrency: 5})
+	if err != nil {
+		t.Fatalf("SendBulk returned error: %v", err)
+	}
+
+	for i, item := range result.Results {
+		if item.Index != i {
+			t.Fatalf("Results[%d].Index = %d, want %d", i, item.Index, i)
+		}
+		wantFail := i%3 == 0
+		if item.Success == wantFail {
+			t.Fatalf("Results[%d].Success = %v for recipient %q, want Success=%v", i, item.Success, messages[i].Recipient.Address, !wantFail)
+		}
+		if wantFail && item.Err == nil {
+			t.Fatalf("Results[%d] expected a *SWUError, got nil", i)
+		}
+	}
+}
+
+func TestSendBulkSetsIdempotencyKeyPerMessage(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		keys = map[string]string{}
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var email SWUEmail
+		_ = json.Unmarshal(body, &email)
+
+		mu.Lock()
+		keys[email.Recipient.Address] = r.Header.Get("Idempotency-Key")
+		mu.Unlock()
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	c := newTestClient(server.URL)
+	messages := []*SWUEmail{
+		{Recipient: &SWURecipient{Address: "a@example.com"}},
+		{Recipient: &SWURecipient{Address: "b@example.com"}},
+		{Recipient: &SWURecipient{Address: "c@example.com"}},
+	}
+	opts := &BulkOptions{
+		Concurrency:     3,
+		IdempotencyKeys: []string{"key-a", "key-b", "key-c"},
+	}
+
+	if _, err := c.SendBulk(messages, opts); err != nil {
+		t.Fatalf("SendBulk returned error: %v", err)
+	}
+
+	want := map[string]string{
+		"a@example.com": "key-a",
+		"b@example.com": "key-b",
+		"c@example.com": "key-c",
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for addr, wantKey := range want {
+		if keys[addr] != wantKey {
+			t.Fatalf("Idempotency-Key for %s = %q, want %q", addr, keys[addr], wantKey)
+		}
+	}
+}
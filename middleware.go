@@ -0,0 +1,47 @@
+package sendwithus
+
+import (
+	"log"
+	"net/http"
+	"time"
+)
+
+// RoundTripFunc performs a single HTTP round trip, analogous to
+// http.RoundTripper.RoundTrip.
+type RoundTripFunc func(*http.Request) (*http.Response, error)
+
+// Middleware wraps a RoundTripFunc to observe or modify requests and
+// responses, e.g. for logging, tracing, or rate limiting. Middlewares are
+// applied in the order they appear in SWUClient.Middlewares, so the first
+// middleware sees the request first and the response last.
+type Middleware func(RoundTripFunc) RoundTripFunc
+
+// roundTrip executes r through c.Client.Do, wrapped by any configured
+// middlewares. With no middlewares configured, behavior is unchanged from
+// calling c.Client.Do(r) directly.
+func (c *SWUClient) roundTrip(r *http.Request) (*http.Response, error) {
+	next := RoundTripFunc(c.Client.Do)
+	for i := len(c.Middlewares) - 1; i >= 0; i-- {
+		next = c.Middlewares[i](next)
+	}
+	return next(r)
+}
+
+// LoggingMiddleware returns a Middleware that logs each request's method,
+// URL path and duration via logger. The API key set by SetBasicAuth is never
+// logged.
+func LoggingMiddleware(logger *log.Logger) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(r *http.Request) (*http.Response, error) {
+			start := time.Now()
+			res, err := next(r)
+			elapsed := time.Since(start)
+			if err != nil {
+				logger.Printf("swu.go: %s %s failed after %s: %s", r.Method, r.URL.Path, elapsed, err)
+				return res, err
+			}
+			logger.Printf("swu.go: %s %s -> %d in %s", r.Method, r.URL.Path, res.StatusCode, elapsed)
+			return res, err
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package sendwithus
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func okResponse() *http.Response {
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(""))}
+}
+
+func labelMiddleware(label string, order *[]string) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(r *http.Request) (*http.Response, error) {
+			*order = append(*order, label+":request")
+			res, err := next(r)
+			*order = append(*order, label+":response")
+			return res, err
+		}
+	}
+}
+
+func TestMiddlewaresRunInOrder(t *testing.T) {
+	var order []string
+	c := New("test-key")
+	c.Client = &http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return okResponse(), nil
+	})}
+	c.Middlewares = []Middleware{
+		labelMiddleware("first", &order),
+		labelMiddleware("second", &order),
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	if _, err := c.roundTrip(req); err != nil {
+		t.Fatalf("roundTrip returned error: %v", err)
+	}
+
+	want := []string{"first:request", "second:request", "second:response", "first:response"}
+	if len(order) != len(want) {
+		t.Fatalf("order = %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("order = %v, want %v", order, want)
+		}
+	}
+}
+
+func TestRateLimiterMiddlewareThrottles(t *testing.T) {
+	limiter := rate.NewLimiter(rate.Limit(20), 1) // 1 immediate token, then 1 every 50ms
+	c := New("test-key")
+	c.Client = &http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return okResponse(), nil
+	})}
+	c.Middlewares = []Middleware{RateLimiterMiddleware(limiter)}
+
+	newReq := func() *http.Request {
+		req, err := http.NewRequest(http.MethodGet, "http://example.com", nil)
+		if err != nil {
+			t.Fatalf("NewRequest: %v", err)
+		}
+		return req
+	}
+
+	start := time.Now()
+	if _, err := c.roundTrip(newReq()); err != nil {
+		t.Fatalf("first roundTrip returned error: %v", err)
+	}
+	firstElapsed := time.Since(start)
+	if firstElapsed > 10*time.Millisecond {
+		t.Fatalf("first request took %v, want near-instant (burst token available)", firstElapsed)
+	}
+
+	start = time.Now()
+	if _, err := c.roundTrip(newReq()); err != nil {
+		t.Fatalf("second roundTrip returned error: %v", err)
+	}
+	secondElapsed := time.Since(start)
+	if secondElapsed < 30*time.Millisecond {
+		t.Fatalf("second request took %v, want it throttled to roughly the limiter's rate", secondElapsed)
+	}
+}
+
+func TestRateLimiterMiddlewareRespectsContextCancellation(t *testing.T) {
+	limiter := rate.NewLimiter(rate.Limit(0.001), 1)
+	limiter.Allow() // take the only token; the next one is ~1000s away
+	called := false
+	c := New("test-key")
+	c.Client = &http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		called = true
+		return okResponse(), nil
+	})}
+	c.Middlewares = []Middleware{RateLimiterMiddleware(limiter)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com", nil)
+	if err != nil {
+		t.Fatalf("NewRequestWithContext: %v", err)
+	}
+
+	start := time.Now()
+	_, err = c.roundTrip(req)
+	elapsed := time.Since(start)
+
+	if err == nil {
+		t.Fatal("roundTrip succeeded, want context deadline error while waiting on the limiter")
+	}
+	if elapsed > 200*time.Millisecond {
+		t.Fatalf("roundTrip took %v to fail, want it to return promptly once the context expires", elapsed)
+	}
+	if called {
+		t.Fatal("next() was called despite the limiter never granting a token")
+	}
+}
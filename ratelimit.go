@@ -0,0 +1,23 @@
+package sendwithus
+
+import (
+	"net/http"
+
+	"golang.org/x/time/rate"
+)
+
+// RateLimiterMiddleware returns a Middleware that blocks until limiter
+// permits a request to proceed, so callers stay within SendWithUs's
+// documented per-account rate limits before the request is even sent. It
+// respects the request's context, returning its error if the context is
+// canceled while waiting.
+func RateLimiterMiddleware(limiter *rate.Limiter) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(r *http.Request) (*http.Response, error) {
+			if err := limiter.Wait(r.Context()); err != nil {
+				return nil, err
+			}
+			return next(r)
+		}
+	}
+}
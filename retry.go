@@ -0,0 +1,90 @@
+package sendwithus
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy configures automatic retries for SWUClient requests. A nil
+// policy (the default) disables retries entirely, preserving the client's
+// original behavior of returning immediately on any non-2xx response.
+//
+// GET requests are retried unconditionally up to MaxAttempts, since they're
+// idempotent. POST requests are only retried when the request carries an
+// Idempotency-Key header (see SendBulk), so a retry can't cause a duplicate
+// send.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Jitter      bool
+}
+
+// DefaultRetryPolicy returns a reasonable retry policy: 3 attempts, starting
+// at 500ms and backing off exponentially up to 10s, with jitter enabled.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    10 * time.Second,
+		Jitter:      true,
+	}
+}
+
+func (p *RetryPolicy) shouldRetry(method string, header http.Header) bool {
+	if p == nil {
+		return false
+	}
+	if method == "GET" {
+		return true
+	}
+	return method == "POST" && header.Get("Idempotency-Key") != ""
+}
+
+// maxBackoffShift caps the shift count passed to backoff's left shift. The
+// shifted delay can still overflow time.Duration's int64 range; backoff
+// falls back to MaxDelay when it does.
+const maxBackoffShift = 62
+
+// backoff returns the delay to wait before the given retry attempt (attempt
+// is 1 for the first retry, 2 for the second, and so on).
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	shift := attempt - 1
+	if shift > maxBackoffShift {
+		shift = maxBackoffShift
+	}
+	delay := p.BaseDelay << uint(shift)
+	// delay <= 0 means the shift overflowed int64; fall back to MaxDelay.
+	if delay <= 0 || (p.MaxDelay > 0 && delay > p.MaxDelay) {
+		delay = p.MaxDelay
+	}
+	if p.Jitter && delay > 0 {
+		delay = time.Duration(rand.Int63n(int64(delay)/2+1)) + delay/2
+	}
+	return delay
+}
+
+// parseRetryAfter parses a Retry-After header value in either delta-seconds
+// or HTTP-date form, returning the duration to wait. It returns false if the
+// header is absent or unparseable.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			secs = 0
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
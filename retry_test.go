@@ -0,0 +1,190 @@
+package sendwithus
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantOK  bool
+		wantMin time.Duration
+		wantMax time.Duration
+	}{
+		{
+			name:   "empty",
+			value:  "",
+			wantOK: false,
+		},
+		{
+			name:    "delta seconds",
+			value:   "120",
+			wantOK:  true,
+			wantMin: 120 * time.Second,
+			wantMax: 120 * time.Second,
+		},
+		{
+			name:    "negative delta seconds clamps to zero",
+			value:   "-5",
+			wantOK:  true,
+			wantMin: 0,
+			wantMax: 0,
+		},
+		{
+			name:    "http date in the future",
+			value:   time.Now().Add(90 * time.Second).UTC().Format(http.TimeFormat),
+			wantOK:  true,
+			wantMin: 85 * time.Second,
+			wantMax: 90 * time.Second,
+		},
+		{
+			name:    "http date in the past clamps to zero",
+			value:   time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat),
+			wantOK:  true,
+			wantMin: 0,
+			wantMax: 0,
+		},
+		{
+			name:   "garbage",
+			value:  "not-a-valid-value",
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseRetryAfter(tt.value)
+			if ok != tt.wantOK {
+				t.Fatalf("parseRetryAfter(%q) ok = %v, want %v", tt.value, ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if got < tt.wantMin || got > tt.wantMax {
+				t.Fatalf("parseRetryAfter(%q) = %v, want between %v and %v", tt.value, got, tt.wantMin, tt.wantMax)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	t.Run("doubles each attempt with no jitter", func(t *testing.T) {
+		p := &RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: 10 * time.Second}
+		if got := p.backoff(1); got != 100*time.Millisecond {
+			t.Fatalf("backoff(1) = %v, want %v", got, 100*time.Millisecond)
+		}
+		if got := p.backoff(2); got != 200*time.Millisecond {
+			t.Fatalf("backoff(2) = %v, want %v", got, 200*time.Millisecond)
+		}
+		if got := p.backoff(3); got != 400*time.Millisecond {
+			t.Fatalf("backoff(3) = %v, want %v", got, 400*time.Millisecond)
+		}
+	})
+
+	t.Run("caps at MaxDelay", func(t *testing.T) {
+		p := &RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+		if got := p.backoff(10); got != time.Second {
+			t.Fatalf("backoff(10) = %v, want %v", got, time.Second)
+		}
+	})
+
+	t.Run("does not overflow for large attempt counts", func(t *testing.T) {
+		p := &RetryPolicy{BaseDelay: 500 * time.Millisecond, MaxDelay: 10 * time.Second}
+		got := p.backoff(1000)
+		if got != 10*time.Second {
+			t.Fatalf("backoff(1000) = %v, want %v (MaxDelay)", got, p.MaxDelay)
+		}
+	})
+
+	t.Run("jitter stays within [delay/2, delay]", func(t *testing.T) {
+		p := &RetryPolicy{BaseDelay: 200 * time.Millisecond, MaxDelay: 10 * time.Second, Jitter: true}
+		for i := 0; i < 50; i++ {
+			got := p.backoff(2)
+			if got < 200*time.Millisecond || got > 400*time.Millisecond {
+				t.Fatalf("backoff(2) with jitter = %v, want between 200ms and 400ms", got)
+			}
+		}
+	})
+}
+
+// flakyTransport fails the first failUntil round trips with a transport
+// error (simulating a connection reset / timeout / DNS failure), then
+// delegates to next.
+type flakyTransport struct {
+	mu        sync.Mutex
+	calls     int
+	failUntil int
+	next      http.RoundTripper
+}
+
+func (t *flakyTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	t.calls++
+	call := t.calls
+	t.mu.Unlock()
+	if call <= t.failUntil {
+		return nil, errors.New("connection reset by peer")
+	}
+	return t.next.RoundTrip(r)
+}
+
+func (t *flakyTransport) callCount() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.calls
+}
+
+func TestMakeRequestRetriesTransportErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+
+	t.Run("retries past transport errors up to MaxAttempts", func(t *testing.T) {
+		transport := &flakyTransport{failUntil: 2, next: http.DefaultTransport}
+		c := newTestClient(server.URL)
+		c.Client = &http.Client{Transport: transport}
+		c.Retry = &RetryPolicy{MaxAttempts: 5, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+		if _, err := c.Emails(); err != nil {
+			t.Fatalf("Emails() returned error: %v", err)
+		}
+		if got := transport.callCount(); got != 3 {
+			t.Fatalf("call count = %d, want 3 (2 failures + 1 success)", got)
+		}
+	})
+
+	t.Run("gives up after MaxAttempts transport errors", func(t *testing.T) {
+		transport := &flakyTransport{failUntil: 10, next: http.DefaultTransport}
+		c := newTestClient(server.URL)
+		c.Client = &http.Client{Transport: transport}
+		c.Retry = &RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+		if _, err := c.Emails(); err == nil {
+			t.Fatal("Emails() succeeded, want error after exhausting retries")
+		}
+		if got := transport.callCount(); got != 3 {
+			t.Fatalf("call count = %d, want 3 (MaxAttempts)", got)
+		}
+	})
+
+	t.Run("no retry policy means a single attempt", func(t *testing.T) {
+		transport := &flakyTransport{failUntil: 1, next: http.DefaultTransport}
+		c := newTestClient(server.URL)
+		c.Client = &http.Client{Transport: transport}
+
+		if _, err := c.Emails(); err == nil {
+			t.Fatal("Emails() succeeded, want error with no retry policy set")
+		}
+		if got := transport.callCount(); got != 1 {
+			t.Fatalf("call count = %d, want 1 (no retries)", got)
+		}
+	})
+}
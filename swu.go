@@ -2,11 +2,15 @@ package sendwithus
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"time"
+
+	stderrors "errors"
 
 	"github.com/google/go-querystring/query"
 	"github.com/superhuman/backend/lib/errors"
@@ -22,6 +26,16 @@ type SWUClient struct {
 	Client *http.Client
 	apiKey string
 	URL    string
+
+	// Retry configures automatic retries for requests. It's nil (no
+	// retries) by default to preserve existing behavior; set it to
+	// DefaultRetryPolicy() or a custom *RetryPolicy to opt in.
+	Retry *RetryPolicy
+
+	// Middlewares wraps every outbound request, in order, e.g. for
+	// observability, logging, or rate limiting. It's empty by default, so
+	// behavior is unchanged from calling Client.Do directly.
+	Middlewares []Middleware
 }
 
 // SWUTemplate describes a SendWithUs template.
@@ -46,12 +60,22 @@ type SWUVersion struct {
 
 // SWUEmail describes a SendWithUs email.
 type SWUEmail struct {
-	ID          string            `json:"email_id,omitempty"`
-	Recipient   *SWURecipient     `json:"recipient,omitempty"`
-	CC          []*SWURecipient   `json:"cc,omitempty"`
-	BCC         []*SWURecipient   `json:"bcc,omitempty"`
-	Sender      *SWUSender        `json:"sender,omitempty"`
-	EmailData   map[string]string `json:"email_data,omitempty"`
+	ID        string          `json:"email_id,omitempty"`
+	Recipient *SWURecipient   `json:"recipient,omitempty"`
+	CC        []*SWURecipient `json:"cc,omitempty"`
+	BCC       []*SWURecipient `json:"bcc,omitempty"`
+	Sender    *SWUSender      `json:"sender,omitempty"`
+
+	// EmailData is deprecated: it can't represent nested objects, arrays,
+	// or numbers. Prefer EmailDataJSON, which takes priority when both are
+	// set. Marshaled via MarshalJSON, not a struct tag.
+	EmailData map[string]string `json:"-"`
+
+	// EmailDataJSON holds template variables as arbitrary JSON, for
+	// templates that need nested objects, arrays, or non-string values.
+	// Use StringMap to convert an existing EmailData map.
+	EmailDataJSON map[string]interface{} `json:"-"`
+
 	Headers     map[string]string `json:"headers,omitempty"`
 	Tags        []string          `json:"tags,omitempty"`
 	Inline      *SWUAttachment    `json:"inline,omitempty"`
@@ -60,19 +84,72 @@ type SWUEmail struct {
 	VersionName string            `json:"version_name,omitempty"`
 }
 
+// MarshalJSON implements json.Marshaler, emitting EmailDataJSON as
+// "email_data" when set, falling back to EmailData otherwise.
+func (e *SWUEmail) MarshalJSON() ([]byte, error) {
+	type alias SWUEmail
+	return json.Marshal(struct {
+		*alias
+		EmailData map[string]interface{} `json:"email_data,omitempty"`
+	}{
+		alias:     (*alias)(e),
+		EmailData: emailData(e.EmailData, e.EmailDataJSON),
+	})
+}
+
 // SWUDripCampaign describes a SendWithUs drip campaign.
 type SWUDripCampaign struct {
-	Recipient  *SWURecipient     `json:"recipient,omitempty"`
-	CC         []*SWURecipient   `json:"cc,omitempty"`
-	BCC        []*SWURecipient   `json:"bcc,omitempty"`
-	Sender     *SWUSender        `json:"sender,omitempty"`
-	EmailData  map[string]string `json:"email_data,omitempty"`
+	Recipient *SWURecipient   `json:"recipient,omitempty"`
+	CC        []*SWURecipient `json:"cc,omitempty"`
+	BCC       []*SWURecipient `json:"bcc,omitempty"`
+	Sender    *SWUSender      `json:"sender,omitempty"`
+
+	// EmailData is deprecated: see SWUEmail.EmailData.
+	EmailData map[string]string `json:"-"`
+
+	// EmailDataJSON takes priority over EmailData when both are set. See
+	// SWUEmail.EmailDataJSON.
+	EmailDataJSON map[string]interface{} `json:"-"`
+
 	Headers    map[string]string `json:"headers,omitempty"`
 	Tags       []string          `json:"tags,omitempty"`
 	ESPAccount string            `json:"esp_account,omitempty"`
 	Locale     string            `json:"locale,omitempty"`
 }
 
+// MarshalJSON implements json.Marshaler, emitting EmailDataJSON as
+// "email_data" when set, falling back to EmailData otherwise.
+func (d *SWUDripCampaign) MarshalJSON() ([]byte, error) {
+	type alias SWUDripCampaign
+	return json.Marshal(struct {
+		*alias
+		EmailData map[string]interface{} `json:"email_data,omitempty"`
+	}{
+		alias:     (*alias)(d),
+		EmailData: emailData(d.EmailData, d.EmailDataJSON),
+	})
+}
+
+func emailData(strs map[string]string, structured map[string]interface{}) map[string]interface{} {
+	if structured != nil {
+		return structured
+	}
+	if strs != nil {
+		return StringMap(strs)
+	}
+	return nil
+}
+
+// StringMap converts a map[string]string to a map[string]interface{}, for
+// populating EmailDataJSON from an existing EmailData map.
+func StringMap(m map[string]string) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
 // SWURecipient describes a SendWithUs recipient.
 type SWURecipient struct {
 	Address string `json:"address,omitempty"`
@@ -133,10 +210,34 @@ type SWULogResend struct {
 	} `json:"email"`
 }
 
-// SWUError describes a SendWithUs error.
+// Sentinel errors that SWUError.Is bridges to based on the response status
+// code, so callers can use errors.Is(err, sendwithus.ErrNotFound) instead of
+// inspecting SWUError.Code directly.
+var (
+	ErrRateLimited    = stderrors.New("sendwithus: rate limited")
+	ErrUnauthorized   = stderrors.New("sendwithus: unauthorized")
+	ErrNotFound       = stderrors.New("sendwithus: not found")
+	ErrInvalidRequest = stderrors.New("sendwithus: invalid request")
+	ErrServer         = stderrors.New("sendwithus: server error")
+)
+
+// SWUError describes a SendWithUs error. When the response body is a
+// SendWithUs JSON error envelope, Message, Type and Param are populated
+// from it; otherwise Message holds the raw response body. RequestID comes
+// from the response's X-Request-Id header.
 type SWUError struct {
-	Code    int
-	Message string
+	Code      int
+	Message   string
+	Type      string
+	Param     string
+	RequestID string
+}
+
+// swuErrorEnvelope is the JSON shape of a SendWithUs API error response.
+type swuErrorEnvelope struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+	Param   string `json:"param"`
 }
 
 func newSWUError(res *http.Response, message string) *SWUError {
@@ -145,7 +246,18 @@ func newSWUError(res *http.Response, message string) *SWUError {
 	}
 	if res != nil {
 		s.Code = res.StatusCode
+		s.RequestID = res.Header.Get("X-Request-Id")
+	}
+
+	var envelope swuErrorEnvelope
+	if json.Unmarshal([]byte(message), &envelope) == nil {
+		if envelope.Message != "" {
+			s.Message = envelope.Message
+		}
+		s.Type = envelope.Type
+		s.Param = envelope.Param
 	}
+
 	return s
 }
 
@@ -154,6 +266,25 @@ func (e *SWUError) Error() string {
 	return fmt.Sprintf("swu.go: Status code: %d, Error: %s", e.Code, e.Message)
 }
 
+// Is implements errors.Is support, bridging to the package's sentinel
+// errors based on Code so callers can write errors.Is(err, ErrNotFound)
+// instead of comparing Code directly.
+func (e *SWUError) Is(target error) bool {
+	switch target {
+	case ErrRateLimited:
+		return e.Code == http.StatusTooManyRequests
+	case ErrUnauthorized:
+		return e.Code == http.StatusUnauthorized
+	case ErrNotFound:
+		return e.Code == http.StatusNotFound
+	case ErrInvalidRequest:
+		return e.Code == http.StatusBadRequest || e.Code == http.StatusUnprocessableEntity
+	case ErrServer:
+		return e.Code >= http.StatusInternalServerError
+	}
+	return false
+}
+
 // New initializes a new SWUClient.
 func New(apiKey string) *SWUClient {
 	return &SWUClient{
@@ -165,13 +296,23 @@ func New(apiKey string) *SWUClient {
 
 // Templates executes a SendWithUs api call.
 func (c *SWUClient) Templates() ([]*SWUTemplate, error) {
-	return c.Emails()
+	return c.TemplatesCtx(context.Background())
+}
+
+// TemplatesCtx executes a SendWithUs api call.
+func (c *SWUClient) TemplatesCtx(ctx context.Context) ([]*SWUTemplate, error) {
+	return c.EmailsCtx(ctx)
 }
 
 // Emails executes a SendWithUs api call.
 func (c *SWUClient) Emails() ([]*SWUTemplate, error) {
+	return c.EmailsCtx(context.Background())
+}
+
+// EmailsCtx executes a SendWithUs api call.
+func (c *SWUClient) EmailsCtx(ctx context.Context) ([]*SWUTemplate, error) {
 	var parse []*SWUTemplate
-	if err := c.makeRequest("GET", "/templates", nil, &parse); err != nil {
+	if err := c.makeRequest(ctx, "GET", "/templates", nil, &parse); err != nil {
 		return nil, errors.Wrap(err)
 	}
 	return parse, nil
@@ -179,8 +320,13 @@ func (c *SWUClient) Emails() ([]*SWUTemplate, error) {
 
 // GetTemplate executes a SendWithUs api call.
 func (c *SWUClient) GetTemplate(id string) (*SWUTemplate, error) {
+	return c.GetTemplateCtx(context.Background(), id)
+}
+
+// GetTemplateCtx executes a SendWithUs api call.
+func (c *SWUClient) GetTemplateCtx(ctx context.Context, id string) (*SWUTemplate, error) {
 	var parse SWUTemplate
-	if err := c.makeRequest("GET", "/templates/"+id, nil, &parse); err != nil {
+	if err := c.makeRequest(ctx, "GET", "/templates/"+id, nil, &parse); err != nil {
 		return nil, errors.Wrap(err)
 	}
 	return &parse, nil
@@ -188,8 +334,13 @@ func (c *SWUClient) GetTemplate(id string) (*SWUTemplate, error) {
 
 // GetTemplateVersion executes a SendWithUs api call.
 func (c *SWUClient) GetTemplateVersion(id, version string) (*SWUVersion, error) {
+	return c.GetTemplateVersionCtx(context.Background(), id, version)
+}
+
+// GetTemplateVersionCtx executes a SendWithUs api call.
+func (c *SWUClient) GetTemplateVersionCtx(ctx context.Context, id, version string) (*SWUVersion, error) {
 	var parse SWUVersion
-	if err := c.makeRequest("GET", "/templates/"+id+"/versions/"+version, nil, &parse); err != nil {
+	if err := c.makeRequest(ctx, "GET", "/templates/"+id+"/versions/"+version, nil, &parse); err != nil {
 		return nil, errors.Wrap(err)
 	}
 	return &parse, nil
@@ -197,12 +348,17 @@ func (c *SWUClient) GetTemplateVersion(id, version string) (*SWUVersion, error)
 
 // UpdateTemplateVersion executes a SendWithUs api call.
 func (c *SWUClient) UpdateTemplateVersion(id, version string, template *SWUVersion) (*SWUVersion, error) {
+	return c.UpdateTemplateVersionCtx(context.Background(), id, version, template)
+}
+
+// UpdateTemplateVersionCtx executes a SendWithUs api call.
+func (c *SWUClient) UpdateTemplateVersionCtx(ctx context.Context, id, version string, template *SWUVersion) (*SWUVersion, error) {
 	var parse SWUVersion
 	payload, err := json.Marshal(template)
 	if err != nil {
 		return nil, errors.Wrap(err)
 	}
-	if err := c.makeRequest("PUT", "/templates/"+id+"/versions/"+version, bytes.NewReader(payload), &parse); err != nil {
+	if err := c.makeRequest(ctx, "PUT", "/templates/"+id+"/versions/"+version, bytes.NewReader(payload), &parse); err != nil {
 		return nil, errors.Wrap(err)
 	}
 	return &parse, nil
@@ -210,12 +366,17 @@ func (c *SWUClient) UpdateTemplateVersion(id, version string, template *SWUVersi
 
 // CreateTemplate executes a SendWithUs api call.
 func (c *SWUClient) CreateTemplate(template *SWUVersion) (*SWUTemplate, error) {
+	return c.CreateTemplateCtx(context.Background(), template)
+}
+
+// CreateTemplateCtx executes a SendWithUs api call.
+func (c *SWUClient) CreateTemplateCtx(ctx context.Context, template *SWUVersion) (*SWUTemplate, error) {
 	var parse SWUTemplate
 	payload, err := json.Marshal(template)
 	if err != nil {
 		return nil, errors.Wrap(err)
 	}
-	if err := c.makeRequest("POST", "/templates", bytes.NewReader(payload), &parse); err != nil {
+	if err := c.makeRequest(ctx, "POST", "/templates", bytes.NewReader(payload), &parse); err != nil {
 		return nil, errors.Wrap(err)
 	}
 	return &parse, nil
@@ -223,12 +384,17 @@ func (c *SWUClient) CreateTemplate(template *SWUVersion) (*SWUTemplate, error) {
 
 // CreateTemplateVersion executes a SendWithUs api call.
 func (c *SWUClient) CreateTemplateVersion(id string, template *SWUVersion) (*SWUTemplate, error) {
+	return c.CreateTemplateVersionCtx(context.Background(), id, template)
+}
+
+// CreateTemplateVersionCtx executes a SendWithUs api call.
+func (c *SWUClient) CreateTemplateVersionCtx(ctx context.Context, id string, template *SWUVersion) (*SWUTemplate, error) {
 	var parse SWUTemplate
 	payload, err := json.Marshal(template)
 	if err != nil {
 		return nil, errors.Wrap(err)
 	}
-	if err := c.makeRequest("POST", "/templates/"+id+"/versions", bytes.NewReader(payload), &parse); err != nil {
+	if err := c.makeRequest(ctx, "POST", "/templates/"+id+"/versions", bytes.NewReader(payload), &parse); err != nil {
 		return nil, errors.Wrap(err)
 	}
 	return &parse, nil
@@ -236,11 +402,16 @@ func (c *SWUClient) CreateTemplateVersion(id string, template *SWUVersion) (*SWU
 
 // Send executes a SendWithUs api call.
 func (c *SWUClient) Send(email *SWUEmail) error {
+	return c.SendCtx(context.Background(), email)
+}
+
+// SendCtx executes a SendWithUs api call.
+func (c *SWUClient) SendCtx(ctx context.Context, email *SWUEmail) error {
 	payload, err := json.Marshal(email)
 	if err != nil {
 		return errors.Wrap(err)
 	}
-	if err := c.makeRequest("POST", "/send", bytes.NewReader(payload), nil); err != nil {
+	if err := c.makeRequest(ctx, "POST", "/send", bytes.NewReader(payload), nil); err != nil {
 		return errors.Wrap(err)
 	}
 	return nil
@@ -248,11 +419,16 @@ func (c *SWUClient) Send(email *SWUEmail) error {
 
 // ActivateDripCampaign executes a SendWithUs api call.
 func (c *SWUClient) ActivateDripCampaign(id string, dripCampaign *SWUDripCampaign) error {
+	return c.ActivateDripCampaignCtx(context.Background(), id, dripCampaign)
+}
+
+// ActivateDripCampaignCtx executes a SendWithUs api call.
+func (c *SWUClient) ActivateDripCampaignCtx(ctx context.Context, id string, dripCampaign *SWUDripCampaign) error {
 	payload, err := json.Marshal(dripCampaign)
 	if err != nil {
 		return errors.Wrap(err)
 	}
-	if err := c.makeRequest("POST", "/drip_campaigns/"+id+"/activate", bytes.NewReader(payload), nil); err != nil {
+	if err := c.makeRequest(ctx, "POST", "/drip_campaigns/"+id+"/activate", bytes.NewReader(payload), nil); err != nil {
 		return errors.Wrap(err)
 	}
 	return nil
@@ -260,12 +436,17 @@ func (c *SWUClient) ActivateDripCampaign(id string, dripCampaign *SWUDripCampaig
 
 // GetLogs executes a SendWithUs api call.
 func (c *SWUClient) GetLogs(q *SWULogQuery) ([]*SWULog, error) {
+	return c.GetLogsCtx(context.Background(), q)
+}
+
+// GetLogsCtx executes a SendWithUs api call.
+func (c *SWUClient) GetLogsCtx(ctx context.Context, q *SWULogQuery) ([]*SWULog, error) {
 	var parse []*SWULog
 	payload, err := query.Values(q)
 	if err != nil {
 		return nil, errors.Wrap(err)
 	}
-	if err := c.makeRequest("GET", "/logs?"+payload.Encode(), nil, &parse); err != nil {
+	if err := c.makeRequest(ctx, "GET", "/logs?"+payload.Encode(), nil, &parse); err != nil {
 		return nil, errors.Wrap(err)
 	}
 	return parse, nil
@@ -273,8 +454,13 @@ func (c *SWUClient) GetLogs(q *SWULogQuery) ([]*SWULog, error) {
 
 // GetLog executes a SendWithUs api call.
 func (c *SWUClient) GetLog(id string) (*SWULog, error) {
+	return c.GetLogCtx(context.Background(), id)
+}
+
+// GetLogCtx executes a SendWithUs api call.
+func (c *SWUClient) GetLogCtx(ctx context.Context, id string) (*SWULog, error) {
 	var parse SWULog
-	if err := c.makeRequest("GET", "/logs/"+id, nil, &parse); err != nil {
+	if err := c.makeRequest(ctx, "GET", "/logs/"+id, nil, &parse); err != nil {
 		return nil, errors.Wrap(err)
 	}
 	return &parse, nil
@@ -282,8 +468,13 @@ func (c *SWUClient) GetLog(id string) (*SWULog, error) {
 
 // GetLogEvents executes a SendWithUs api call.
 func (c *SWUClient) GetLogEvents(id string) (*SWULogEvent, error) {
+	return c.GetLogEventsCtx(context.Background(), id)
+}
+
+// GetLogEventsCtx executes a SendWithUs api call.
+func (c *SWUClient) GetLogEventsCtx(ctx context.Context, id string) (*SWULogEvent, error) {
 	var parse SWULogEvent
-	if err := c.makeRequest("GET", "/logs/"+id+"/events", nil, &parse); err != nil {
+	if err := c.makeRequest(ctx, "GET", "/logs/"+id+"/events", nil, &parse); err != nil {
 		return nil, errors.Wrap(err)
 	}
 	return &parse, nil
@@ -291,6 +482,11 @@ func (c *SWUClient) GetLogEvents(id string) (*SWULogEvent, error) {
 
 // ResendLog executes a SendWithUs api call.
 func (c *SWUClient) ResendLog(id string) (*SWULogResend, error) {
+	return c.ResendLogCtx(context.Background(), id)
+}
+
+// ResendLogCtx executes a SendWithUs api call.
+func (c *SWUClient) ResendLogCtx(ctx context.Context, id string) (*SWULogResend, error) {
 	parse := &SWULogResend{
 		ID: id,
 	}
@@ -298,35 +494,85 @@ func (c *SWUClient) ResendLog(id string) (*SWULogResend, error) {
 	if err != nil {
 		return nil, errors.Wrap(err)
 	}
-	if err := c.makeRequest("POST", "/resend", bytes.NewReader(payload), parse); err != nil {
+	if err := c.makeRequest(ctx, "POST", "/resend", bytes.NewReader(payload), parse); err != nil {
 		return nil, errors.Wrap(err)
 	}
 	return parse, nil
 }
 
-func (c *SWUClient) makeRequest(method, endpoint string, body io.Reader, result interface{}) error {
-	r, err := http.NewRequest(method, c.URL+endpoint, body)
-	if err != nil {
-		return errors.Wrap(err)
+func (c *SWUClient) makeRequest(ctx context.Context, method, endpoint string, body io.Reader, result interface{}) error {
+	return c.makeRequestWithHeader(ctx, method, endpoint, body, result, nil)
+}
+
+func (c *SWUClient) makeRequestWithHeader(ctx context.Context, method, endpoint string, body io.Reader, result interface{}, header http.Header) error {
+	seekableBody, _ := body.(io.ReadSeeker)
+	retryable := c.Retry.shouldRetry(method, header)
+
+	for attempt := 1; ; attempt++ {
+		r, err := http.NewRequestWithContext(ctx, method, c.URL+endpoint, body)
+		if err != nil {
+			return errors.Wrap(err)
+		}
+		r.SetBasicAuth(c.apiKey, "")
+		r.Header.Set("X-SWU-API-CLIENT", apiHeaderClient)
+		for k, v := range header {
+			r.Header[k] = v
+		}
+
+		// res is nil here whenever swuErr came from a transport error
+		// (connection reset, timeout, DNS failure, ...) rather than a
+		// non-2xx response, so it carries no status code or headers.
+		res, swuErr, b := c.doRequest(r)
+		if swuErr == nil {
+			if result != nil {
+				return buildRespJSON(b, result)
+			}
+			return nil
+		}
+
+		if !retryable || attempt >= c.Retry.MaxAttempts || seekableBody == nil && body != nil {
+			return errors.Wrap(swuErr)
+		}
+
+		delay := c.Retry.backoff(attempt)
+		if res != nil && res.StatusCode == http.StatusTooManyRequests {
+			if retryAfter, ok := parseRetryAfter(res.Header.Get("Retry-After")); ok && retryAfter > delay {
+				delay = retryAfter
+			}
+		}
+
+		if seekableBody != nil {
+			if _, err := seekableBody.Seek(0, io.SeekStart); err != nil {
+				return errors.Wrap(swuErr)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(newSWUError(nil, ctx.Err().Error()))
+		case <-time.After(delay):
+		}
 	}
-	r.SetBasicAuth(c.apiKey, "")
-	r.Header.Set("X-SWU-API-CLIENT", apiHeaderClient)
-	res, err := c.Client.Do(r)
+}
+
+// doRequest performs a single round trip, normalizing transport errors and
+// non-2xx responses into a *SWUError so the retry loop in
+// makeRequestWithHeader can treat both the same way. swuErr is nil on
+// success, in which case b holds the (already closed) response body.
+func (c *SWUClient) doRequest(r *http.Request) (res *http.Response, swuErr *SWUError, b []byte) {
+	res, err := c.roundTrip(r)
 	if err != nil {
-		return errors.Wrap(newSWUError(res, err.Error()))
+		return nil, newSWUError(nil, err.Error()), nil
 	}
-	defer res.Body.Close()
-	b, err := ioutil.ReadAll(res.Body)
+	b, err = ioutil.ReadAll(res.Body)
+	res.Body.Close()
 	if err != nil {
-		return errors.Wrap(newSWUError(res, err.Error()))
+		return res, newSWUError(res, err.Error()), nil
 	}
 	if res.StatusCode >= 300 {
-		return errors.Wrap(newSWUError(res, string(b)))
-	}
-	if result != nil {
-		return buildRespJSON(b, result)
+		return res, newSWUError(res, string(b)), nil
 	}
-	return nil
+	return res, nil, b
 }
 
 func buildRespJSON(b []byte, parse interface{}) error {
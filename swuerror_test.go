@@ -0,0 +1,120 @@
+package sendwithus
+
+import (
+	stderrors "errors"
+	"net/http"
+	"testing"
+)
+
+func responseWithHeader(code int, header http.Header) *http.Response {
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{StatusCode: code, Header: header}
+}
+
+func TestNewSWUError(t *testing.T) {
+	t.Run("decodes a JSON error envelope", func(t *testing.T) {
+		res := responseWithHeader(http.StatusBadRequest, http.Header{"X-Request-Id": []string{"req-123"}})
+		body := `{"message":"email_id is required","type":"invalid_request","param":"email_id"}`
+
+		err := newSWUError(res, body)
+
+		if err.Code != http.StatusBadRequest {
+			t.Errorf("Code = %d, want %d", err.Code, http.StatusBadRequest)
+		}
+		if err.Message != "email_id is required" {
+			t.Errorf("Message = %q, want %q", err.Message, "email_id is required")
+		}
+		if err.Type != "invalid_request" {
+			t.Errorf("Type = %q, want %q", err.Type, "invalid_request")
+		}
+		if err.Param != "email_id" {
+			t.Errorf("Param = %q, want %q", err.Param, "email_id")
+		}
+		if err.RequestID != "req-123" {
+			t.Errorf("RequestID = %q, want %q", err.RequestID, "req-123")
+		}
+	})
+
+	t.Run("falls back to the raw body when it isn't a JSON envelope", func(t *testing.T) {
+		res := responseWithHeader(http.StatusInternalServerError, nil)
+		body := "internal server error"
+
+		err := newSWUError(res, body)
+
+		if err.Message != body {
+			t.Errorf("Message = %q, want %q", err.Message, body)
+		}
+		if err.Type != "" || err.Param != "" {
+			t.Errorf("Type/Param = %q/%q, want empty for a non-envelope body", err.Type, err.Param)
+		}
+	})
+
+	t.Run("falls back to the raw body for JSON that isn't an envelope", func(t *testing.T) {
+		res := responseWithHeader(http.StatusBadRequest, nil)
+		body := `["not", "an", "envelope"]`
+
+		err := newSWUError(res, body)
+
+		if err.Message != body {
+			t.Errorf("Message = %q, want %q", err.Message, body)
+		}
+	})
+
+	t.Run("handles a nil response", func(t *testing.T) {
+		err := newSWUError(nil, "connection reset by peer")
+
+		if err.Code != 0 {
+			t.Errorf("Code = %d, want 0", err.Code)
+		}
+		if err.Message != "connection reset by peer" {
+			t.Errorf("Message = %q, want %q", err.Message, "connection reset by peer")
+		}
+	})
+}
+
+func TestSWUErrorIs(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    int
+		matches []error
+	}{
+		{name: "429 matches ErrRateLimited", code: http.StatusTooManyRequests, matches: []error{ErrRateLimited}},
+		{name: "401 matches ErrUnauthorized", code: http.StatusUnauthorized, matches: []error{ErrUnauthorized}},
+		{name: "404 matches ErrNotFound", code: http.StatusNotFound, matches: []error{ErrNotFound}},
+		{name: "400 matches ErrInvalidRequest", code: http.StatusBadRequest, matches: []error{ErrInvalidRequest}},
+		{name: "422 matches ErrInvalidRequest", code: http.StatusUnprocessableEntity, matches: []error{ErrInvalidRequest}},
+		{name: "500 matches ErrServer", code: http.StatusInternalServerError, matches: []error{ErrServer}},
+		{name: "503 matches ErrServer", code: http.StatusServiceUnavailable, matches: []error{ErrServer}},
+	}
+
+	allSentinels := []error{ErrRateLimited, ErrUnauthorized, ErrNotFound, ErrInvalidRequest, ErrServer}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newSWUError(responseWithHeader(tt.code, nil), "")
+
+			for _, sentinel := range allSentinels {
+				want := false
+				for _, m := range tt.matches {
+					if m == sentinel {
+						want = true
+					}
+				}
+				if got := stderrors.Is(err, sentinel); got != want {
+					t.Errorf("errors.Is(err, %v) for code %d = %v, want %v", sentinel, tt.code, got, want)
+				}
+			}
+		})
+	}
+
+	t.Run("200 matches no sentinel", func(t *testing.T) {
+		err := newSWUError(responseWithHeader(http.StatusOK, nil), "")
+		for _, sentinel := range allSentinels {
+			if stderrors.Is(err, sentinel) {
+				t.Errorf("errors.Is(err, %v) = true for a 200 status, want false", sentinel)
+			}
+		}
+	})
+}
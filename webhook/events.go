@@ -0,0 +1,91 @@
+// Package webhook receives and dispatches SendWithUs event callbacks
+// (delivered, bounced, opened, clicked, complained, unsubscribed).
+package webhook
+
+// EventType identifies the kind of SendWithUs event a callback describes.
+type EventType string
+
+// Event types as sent in the "type" field of a SendWithUs webhook payload.
+const (
+	EventDelivered    EventType = "delivered"
+	EventBounced      EventType = "bounced"
+	EventOpened       EventType = "opened"
+	EventClicked      EventType = "clicked"
+	EventComplained   EventType = "complained"
+	EventUnsubscribed EventType = "unsubscribed"
+)
+
+// Event is implemented by every typed event struct in this package.
+type Event interface {
+	// Type returns the event's EventType.
+	Type() EventType
+}
+
+// DeliveredEvent describes a SendWithUs delivered event.
+type DeliveredEvent struct {
+	EmailID          string `json:"email_id,omitempty"`
+	EmailName        string `json:"email_name,omitempty"`
+	RecipientAddress string `json:"recipient_address,omitempty"`
+	Created          int64  `json:"created,omitempty"`
+}
+
+// Type implements Event.
+func (DeliveredEvent) Type() EventType { return EventDelivered }
+
+// BouncedEvent describes a SendWithUs bounced event.
+type BouncedEvent struct {
+	EmailID          string `json:"email_id,omitempty"`
+	EmailName        string `json:"email_name,omitempty"`
+	RecipientAddress string `json:"recipient_address,omitempty"`
+	Created          int64  `json:"created,omitempty"`
+	BounceType       string `json:"bounce_type,omitempty"`
+	Reason           string `json:"reason,omitempty"`
+}
+
+// Type implements Event.
+func (BouncedEvent) Type() EventType { return EventBounced }
+
+// OpenedEvent describes a SendWithUs opened event.
+type OpenedEvent struct {
+	EmailID          string `json:"email_id,omitempty"`
+	EmailName        string `json:"email_name,omitempty"`
+	RecipientAddress string `json:"recipient_address,omitempty"`
+	Created          int64  `json:"created,omitempty"`
+}
+
+// Type implements Event.
+func (OpenedEvent) Type() EventType { return EventOpened }
+
+// ClickedEvent describes a SendWithUs clicked event.
+type ClickedEvent struct {
+	EmailID          string `json:"email_id,omitempty"`
+	EmailName        string `json:"email_name,omitempty"`
+	RecipientAddress string `json:"recipient_address,omitempty"`
+	Created          int64  `json:"created,omitempty"`
+	URL              string `json:"url,omitempty"`
+}
+
+// Type implements Event.
+func (ClickedEvent) Type() EventType { return EventClicked }
+
+// ComplainedEvent describes a SendWithUs complained (spam report) event.
+type ComplainedEvent struct {
+	EmailID          string `json:"email_id,omitempty"`
+	EmailName        string `json:"email_name,omitempty"`
+	RecipientAddress string `json:"recipient_address,omitempty"`
+	Created          int64  `json:"created,omitempty"`
+}
+
+// Type implements Event.
+func (ComplainedEvent) Type() EventType { return EventComplained }
+
+// UnsubscribedEvent describes a SendWithUs unsubscribed event.
+type UnsubscribedEvent struct {
+	EmailID          string `json:"email_id,omitempty"`
+	EmailName        string `json:"email_name,omitempty"`
+	RecipientAddress string `json:"recipient_address,omitempty"`
+	Created          int64  `json:"created,omitempty"`
+}
+
+// Type implements Event.
+func (UnsubscribedEvent) Type() EventType { return EventUnsubscribed }
@@ -0,0 +1,159 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+)
+
+const signatureHeader = "X-SendWithUs-Signature"
+
+// Mux is an http.Handler that verifies and dispatches incoming SendWithUs
+// webhook events to registered callbacks.
+type Mux struct {
+	secret string
+
+	onDelivered    []func(DeliveredEvent)
+	onBounced      []func(BouncedEvent)
+	onOpened       []func(OpenedEvent)
+	onClicked      []func(ClickedEvent)
+	onComplained   []func(ComplainedEvent)
+	onUnsubscribed []func(UnsubscribedEvent)
+}
+
+// NewMux initializes a new Mux that verifies incoming webhook requests
+// against the given shared secret.
+func NewMux(secret string) *Mux {
+	return &Mux{secret: secret}
+}
+
+// OnDelivered registers a callback invoked for every delivered event.
+func (m *Mux) OnDelivered(fn func(DeliveredEvent)) {
+	m.onDelivered = append(m.onDelivered, fn)
+}
+
+// OnBounced registers a callback invoked for every bounced event.
+func (m *Mux) OnBounced(fn func(BouncedEvent)) {
+	m.onBounced = append(m.onBounced, fn)
+}
+
+// OnOpened registers a callback invoked for every opened event.
+func (m *Mux) OnOpened(fn func(OpenedEvent)) {
+	m.onOpened = append(m.onOpened, fn)
+}
+
+// OnClicked registers a callback invoked for every clicked event.
+func (m *Mux) OnClicked(fn func(ClickedEvent)) {
+	m.onClicked = append(m.onClicked, fn)
+}
+
+// OnComplained registers a callback invoked for every complained event.
+func (m *Mux) OnComplained(fn func(ComplainedEvent)) {
+	m.onComplained = append(m.onComplained, fn)
+}
+
+// OnUnsubscribed registers a callback invoked for every unsubscribed event.
+func (m *Mux) OnUnsubscribed(fn func(UnsubscribedEvent)) {
+	m.onUnsubscribed = append(m.onUnsubscribed, fn)
+}
+
+// ServeHTTP implements http.Handler. It verifies the request's HMAC-SHA1
+// signature, decodes the event payload, and dispatches it to any callbacks
+// registered for its type.
+func (m *Mux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "webhook: unable to read request body", http.StatusBadRequest)
+		return
+	}
+
+	if !m.verify(r.Header.Get(signatureHeader), body) {
+		http.Error(w, "webhook: invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	var envelope struct {
+		Type EventType `json:"type"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		http.Error(w, "webhook: invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	if err := m.dispatch(envelope.Type, body); err != nil {
+		http.Error(w, "webhook: invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (m *Mux) dispatch(eventType EventType, body []byte) error {
+	switch eventType {
+	case EventDelivered:
+		var ev DeliveredEvent
+		if err := json.Unmarshal(body, &ev); err != nil {
+			return err
+		}
+		for _, fn := range m.onDelivered {
+			fn(ev)
+		}
+	case EventBounced:
+		var ev BouncedEvent
+		if err := json.Unmarshal(body, &ev); err != nil {
+			return err
+		}
+		for _, fn := range m.onBounced {
+			fn(ev)
+		}
+	case EventOpened:
+		var ev OpenedEvent
+		if err := json.Unmarshal(body, &ev); err != nil {
+			return err
+		}
+		for _, fn := range m.onOpened {
+			fn(ev)
+		}
+	case EventClicked:
+		var ev ClickedEvent
+		if err := json.Unmarshal(body, &ev); err != nil {
+			return err
+		}
+		for _, fn := range m.onClicked {
+			fn(ev)
+		}
+	case EventComplained:
+		var ev ComplainedEvent
+		if err := json.Unmarshal(body, &ev); err != nil {
+			return err
+		}
+		for _, fn := range m.onComplained {
+			fn(ev)
+		}
+	case EventUnsubscribed:
+		var ev UnsubscribedEvent
+		if err := json.Unmarshal(body, &ev); err != nil {
+			return err
+		}
+		for _, fn := range m.onUnsubscribed {
+			fn(ev)
+		}
+	}
+	return nil
+}
+
+func (m *Mux) verify(signature string, body []byte) bool {
+	if signature == "" {
+		return false
+	}
+	sig, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha1.New, []byte(m.secret))
+	mac.Write(body)
+	return hmac.Equal(sig, mac.Sum(nil))
+}
@@ -0,0 +1,114 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testSecret = "shared-secret"
+
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha1.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestMuxServeHTTP(t *testing.T) {
+	body := []byte(`{"type":"delivered","email_id":"abc123","recipient_address":"a@example.com"}`)
+	validSig := sign(testSecret, body)
+
+	tests := []struct {
+		name       string
+		body       []byte
+		signature  string
+		wantStatus int
+	}{
+		{
+			name:       "valid signature",
+			body:       body,
+			signature:  validSig,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "valid signature uppercased still verifies",
+			body:       body,
+			signature:  strings.ToUpper(validSig),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "tampered body",
+			body:       []byte(`{"type":"delivered","email_id":"tampered"}`),
+			signature:  validSig,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "missing signature header",
+			body:       body,
+			signature:  "",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "non-hex signature",
+			body:       body,
+			signature:  "not-hex-zzz",
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var delivered *DeliveredEvent
+			m := NewMux(testSecret)
+			m.OnDelivered(func(ev DeliveredEvent) {
+				delivered = &ev
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(string(tt.body)))
+			if tt.signature != "" {
+				req.Header.Set(signatureHeader, tt.signature)
+			}
+			rec := httptest.NewRecorder()
+
+			m.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusOK {
+				if delivered == nil {
+					t.Fatal("OnDelivered callback was not invoked")
+				}
+				if delivered.EmailID != "abc123" {
+					t.Fatalf("delivered.EmailID = %q, want %q", delivered.EmailID, "abc123")
+				}
+			} else if delivered != nil {
+				t.Fatal("OnDelivered callback should not be invoked when verification fails")
+			}
+		})
+	}
+}
+
+func TestMuxDispatchesByEventType(t *testing.T) {
+	var gotBounce BouncedEvent
+	m := NewMux(testSecret)
+	m.OnBounced(func(ev BouncedEvent) { gotBounce = ev })
+
+	body := []byte(`{"type":"bounced","email_id":"xyz","bounce_type":"hard"}`)
+	req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(string(body)))
+	req.Header.Set(signatureHeader, sign(testSecret, body))
+	rec := httptest.NewRecorder()
+
+	m.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if gotBounce.EmailID != "xyz" || gotBounce.BounceType != "hard" {
+		t.Fatalf("gotBounce = %+v, want EmailID=xyz BounceType=hard", gotBounce)
+	}
+}